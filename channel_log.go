@@ -1,6 +1,7 @@
 package courier
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
 	"time"
@@ -95,6 +96,14 @@ func (e *ChannelError) ExtCode() string {
 	return e.extCode
 }
 
+func (e *ChannelError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&struct {
+		Code    string `json:"code"`
+		ExtCode string `json:"ext_code,omitempty"`
+		Message string `json:"message"`
+	}{Code: e.code, ExtCode: e.extCode, Message: e.message})
+}
+
 // ChannelLog stores the HTTP traces and errors generated by an interaction with a channel.
 type ChannelLog struct {
 	uuid      ChannelLogUUID
@@ -164,6 +173,9 @@ func (l *ChannelLog) End() {
 	}
 
 	l.elapsed = time.Since(l.createdOn)
+
+	notifyChannelLogSinks(l)
+	defaultLogHub.Publish(l)
 }
 
 func (l *ChannelLog) UUID() ChannelLogUUID {
@@ -224,3 +236,28 @@ func (l *ChannelLog) IsError() bool {
 func (l *ChannelLog) traceToLog(t *httpx.Trace) *httpx.Log {
 	return httpx.NewLog(t, 2048, 50000, l.redactor)
 }
+
+func (l *ChannelLog) MarshalJSON() ([]byte, error) {
+	var channelUUID ChannelUUID
+	if l.channel != nil {
+		channelUUID = l.channel.UUID()
+	}
+
+	return json.Marshal(&struct {
+		UUID        ChannelLogUUID  `json:"uuid"`
+		ChannelUUID ChannelUUID     `json:"channel_uuid,omitempty"`
+		Type        ChannelLogType  `json:"type"`
+		HTTPLogs    []*httpx.Log    `json:"http_logs"`
+		Errors      []*ChannelError `json:"errors"`
+		CreatedOn   time.Time       `json:"created_on"`
+		ElapsedMS   int64           `json:"elapsed_ms"`
+	}{
+		UUID:        l.uuid,
+		ChannelUUID: channelUUID,
+		Type:        l.type_,
+		HTTPLogs:    l.httpLogs,
+		Errors:      l.errors,
+		CreatedOn:   l.createdOn,
+		ElapsedMS:   l.elapsed.Milliseconds(),
+	})
+}
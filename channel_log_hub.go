@@ -0,0 +1,196 @@
+package courier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	logHubSubscriberBuffer = 64
+	logHubMaxSubscribers   = 50
+	logHubHeartbeat        = 15 * time.Second
+)
+
+// LogHub fans completed channel logs out to subscribers tailing them over SSE. Writers (i.e.
+// ChannelLog.End()) publish to it; publishing never blocks on a slow subscriber - instead, when
+// that subscriber's buffer is full, the log being published is dropped for it and the drop is
+// counted, to be reported to the subscriber as a "missed" event.
+type LogHub struct {
+	mutex       sync.Mutex
+	subscribers map[ChannelUUID]map[*logSubscriber]bool
+}
+
+// NewLogHub creates a new, empty log hub
+func NewLogHub() *LogHub {
+	return &LogHub{subscribers: make(map[ChannelUUID]map[*logSubscriber]bool)}
+}
+
+// defaultLogHub is published to by every ChannelLog.End() call, and is what the server's
+// /c/{channelUUID}/logs/tail route subscribes to
+var defaultLogHub = NewLogHub()
+
+// DefaultLogHub returns the hub that all channel logs are published to as they complete
+func DefaultLogHub() *LogHub {
+	return defaultLogHub
+}
+
+type logSubscriber struct {
+	ch     chan *ChannelLog
+	filter func(*ChannelLog) bool
+	missed atomic.Int64
+}
+
+// Logs returns the channel that logs published to this subscriber are delivered on
+func (s *logSubscriber) Logs() <-chan *ChannelLog {
+	return s.ch
+}
+
+// Publish notifies any subscribers of l's channel that match their filter
+func (h *LogHub) Publish(l *ChannelLog) {
+	if l.Channel() == nil {
+		return
+	}
+
+	h.mutex.Lock()
+	subs := make([]*logSubscriber, 0, len(h.subscribers[l.Channel().UUID()]))
+	for s := range h.subscribers[l.Channel().UUID()] {
+		subs = append(subs, s)
+	}
+	h.mutex.Unlock()
+
+	for _, sub := range subs {
+		if sub.filter != nil && !sub.filter(l) {
+			continue
+		}
+
+		select {
+		case sub.ch <- l:
+		default:
+			sub.missed.Add(1)
+		}
+	}
+}
+
+// Subscribe registers a new subscriber for channelUUID's logs, returning it along with a function
+// that must be called to unsubscribe once the caller is done. Returns an error if the channel
+// already has the maximum number of concurrent subscribers.
+func (h *LogHub) Subscribe(channelUUID ChannelUUID, filter func(*ChannelLog) bool) (*logSubscriber, func(), error) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.subscribers[channelUUID] == nil {
+		h.subscribers[channelUUID] = make(map[*logSubscriber]bool)
+	}
+	if len(h.subscribers[channelUUID]) >= logHubMaxSubscribers {
+		return nil, nil, fmt.Errorf("too many subscribers for channel %s", channelUUID)
+	}
+
+	sub := &logSubscriber{ch: make(chan *ChannelLog, logHubSubscriberBuffer), filter: filter}
+	h.subscribers[channelUUID][sub] = true
+
+	unsubscribe := func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		delete(h.subscribers[channelUUID], sub)
+		if len(h.subscribers[channelUUID]) == 0 {
+			delete(h.subscribers, channelUUID)
+		}
+	}
+	return sub, unsubscribe, nil
+}
+
+// ChannelAuthenticator wraps a handler so that only authenticated requests reach it - the same
+// middleware shape used elsewhere for per-route auth (see handlers.WithHTTPSignature) - so it
+// composes with whatever auth the rest of the server's /c/{channelUUID}/... routes already use.
+type ChannelAuthenticator func(http.Handler) http.Handler
+
+// ChannelResolver looks up a channel by its UUID, as parsed from the request path
+type ChannelResolver func(ctx context.Context, channelUUID ChannelUUID) (Channel, error)
+
+// RegisterTailRoute mounts h's log-tailing endpoint at "GET /c/{channelUUID}/logs/tail" on mux,
+// behind authenticate. resolve looks up the channel named by the {channelUUID} path segment.
+func (h *LogHub) RegisterTailRoute(mux *http.ServeMux, authenticate ChannelAuthenticator, resolve ChannelResolver) {
+	handler := h.ServeTail(func(r *http.Request) (Channel, error) {
+		return resolve(r.Context(), ChannelUUID(r.PathValue("channelUUID")))
+	})
+
+	mux.Handle("GET /c/{channelUUID}/logs/tail", authenticate(handler))
+}
+
+// ServeTail returns a handler for GET /c/{channelUUID}/logs/tail that streams newly created
+// channel logs for the channel resolved by resolveChannel as SSE `data:` frames, until the client
+// disconnects. Supports the query-string filters `type` (a ChannelLogType) and `errors_only`.
+func (h *LogHub) ServeTail(resolveChannel func(r *http.Request) (Channel, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ch, err := resolveChannel(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		q := r.URL.Query()
+		wantType := ChannelLogType(q.Get("type"))
+		errorsOnly := q.Get("errors_only") == "true"
+
+		filter := func(l *ChannelLog) bool {
+			if errorsOnly && !l.IsError() {
+				return false
+			}
+			if wantType != "" && l.Type() != wantType {
+				return false
+			}
+			return true
+		}
+
+		sub, unsubscribe, err := h.Subscribe(ch.UUID(), filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		ticker := time.NewTicker(logHubHeartbeat)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+
+			case l := <-sub.ch:
+				data, err := json.Marshal(l)
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+
+				if missed := sub.missed.Swap(0); missed > 0 {
+					fmt.Fprintf(w, "event: missed\ndata: {\"missed\": %d}\n\n", missed)
+				}
+				flusher.Flush()
+
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package courier_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nyaruka/courier"
+	"github.com/nyaruka/courier/test"
+	"github.com/nyaruka/gocommon/httpx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogHubTail(t *testing.T) {
+	hub := courier.NewLogHub()
+	ch := test.NewMockChannel("8eb23e93-5ecb-45ba-b726-3b064e0c56ab", "XX", "1234", "US", nil)
+
+	server := httptest.NewServer(hub.ServeTail(func(r *http.Request) (courier.Channel, error) {
+		return ch, nil
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// give the subscriber a moment to register before publishing, since Subscribe happens
+	// asynchronously relative to this goroutine issuing the request
+	time.Sleep(50 * time.Millisecond)
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	clog.End()
+	hub.Publish(clog)
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for i := 0; i < 20; i++ {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+			break
+		}
+	}
+	require.NotEmpty(t, data, "expected to receive a data frame")
+
+	var received struct {
+		UUID string `json:"uuid"`
+		Type string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &received))
+	assert.Equal(t, string(clog.UUID()), received.UUID)
+	assert.Equal(t, "msg_send", received.Type)
+}
+
+// TestChannelLogEndPublishesToDefaultLogHub exercises the actual wiring a real channel send uses:
+// ChannelLog.End() publishes to the package-level DefaultLogHub, which is what the server's
+// logs-tail route subscribes to - not a hub constructed and published to by hand.
+func TestChannelLogEndPublishesToDefaultLogHub(t *testing.T) {
+	ch := test.NewMockChannel("8eb23e93-5ecb-45ba-b726-3b064e0c56ab", "XX", "1234", "US", nil)
+
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("pong"))
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(courier.DefaultLogHub().ServeTail(func(r *http.Request) (courier.Channel, error) {
+		return ch, nil
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// give the subscriber a moment to register before publishing, since Subscribe happens
+	// asynchronously relative to this goroutine issuing the request
+	time.Sleep(50 * time.Millisecond)
+
+	req, err := http.NewRequest(http.MethodGet, target.URL, nil)
+	require.NoError(t, err)
+	trace, err := httpx.DoTrace(http.DefaultClient, req, nil, nil, -1)
+	require.NoError(t, err)
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	clog.HTTP(trace)
+	clog.End() // publishes to courier.DefaultLogHub()
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for i := 0; i < 20; i++ {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+			break
+		}
+	}
+	require.NotEmpty(t, data, "expected to receive a data frame")
+
+	var received struct {
+		UUID     string            `json:"uuid"`
+		Type     string            `json:"type"`
+		HTTPLogs []json.RawMessage `json:"http_logs"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(data), &received))
+	assert.Equal(t, string(clog.UUID()), received.UUID)
+	assert.Equal(t, "msg_send", received.Type)
+	assert.Len(t, received.HTTPLogs, 1, "expected the outgoing HTTP trace to survive onto the wire")
+}
+
+func TestLogHubRegisterTailRoute(t *testing.T) {
+	hub := courier.NewLogHub()
+	ch := test.NewMockChannel("8eb23e93-5ecb-45ba-b726-3b064e0c56ab", "XX", "1234", "US", nil)
+
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Token secret" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	resolve := func(ctx context.Context, channelUUID courier.ChannelUUID) (courier.Channel, error) {
+		if channelUUID != ch.UUID() {
+			return nil, assert.AnError
+		}
+		return ch, nil
+	}
+
+	mux := http.NewServeMux()
+	hub.RegisterTailRoute(mux, authenticate, resolve)
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	path := server.URL + "/c/" + string(ch.UUID()) + "/logs/tail"
+
+	// unauthenticated requests never reach the subscriber
+	unauthed, err := http.Get(path)
+	require.NoError(t, err)
+	defer unauthed.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, unauthed.StatusCode)
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Token secret")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	time.Sleep(50 * time.Millisecond)
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	clog.End()
+	hub.Publish(clog)
+
+	reader := bufio.NewReader(resp.Body)
+	var data string
+	for i := 0; i < 20; i++ {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		if strings.HasPrefix(line, "data: ") {
+			data = strings.TrimPrefix(strings.TrimSpace(line), "data: ")
+			break
+		}
+	}
+	require.NotEmpty(t, data)
+}
+
+func TestLogHubFilters(t *testing.T) {
+	hub := courier.NewLogHub()
+	ch := test.NewMockChannel("8eb23e93-5ecb-45ba-b726-3b064e0c56ab", "XX", "1234", "US", nil)
+
+	sub, unsubscribe, err := hub.Subscribe(ch.UUID(), func(l *courier.ChannelLog) bool { return l.IsError() })
+	require.NoError(t, err)
+	defer unsubscribe()
+
+	ok := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	ok.End()
+	hub.Publish(ok)
+
+	failed := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	failed.Error(courier.NewChannelError("test_error", "", "boom"))
+	failed.End()
+	hub.Publish(failed)
+
+	select {
+	case l := <-sub.Logs():
+		assert.Equal(t, failed.UUID(), l.UUID())
+	default:
+		t.Fatal("expected the errored log to be delivered")
+	}
+}
@@ -0,0 +1,122 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ChannelLogSink is a destination that completed channel logs are written to, e.g. for local
+// development or for shipping channel traffic to an external log aggregator.
+type ChannelLogSink interface {
+	// Write is called with a channel log once .End() has been called on it
+	Write(ctx context.Context, l *ChannelLog) error
+}
+
+// ErrorsOnly wraps sink so that it's only invoked for logs where IsError() is true, for use with
+// sinks that would otherwise be too noisy on a busy production channel.
+func ErrorsOnly(sink ChannelLogSink) ChannelLogSink {
+	return &filteredSink{sink: sink, pred: func(l *ChannelLog) bool { return l.IsError() }}
+}
+
+type filteredSink struct {
+	sink ChannelLogSink
+	pred func(*ChannelLog) bool
+}
+
+func (f *filteredSink) Write(ctx context.Context, l *ChannelLog) error {
+	if f.pred != nil && !f.pred(l) {
+		return nil
+	}
+	return f.sink.Write(ctx, l)
+}
+
+var (
+	channelLogSinksMutex sync.RWMutex
+	channelLogSinks      []ChannelLogSink
+)
+
+// RegisterChannelLogSink adds a sink that will be notified of every channel log as it completes.
+// It's intended to be called once at server startup, from the sinks built by ParseChannelLogSinks.
+func RegisterChannelLogSink(s ChannelLogSink) {
+	channelLogSinksMutex.Lock()
+	defer channelLogSinksMutex.Unlock()
+
+	channelLogSinks = append(channelLogSinks, s)
+}
+
+// ClearChannelLogSinks removes all registered sinks, for use in tests
+func ClearChannelLogSinks() {
+	channelLogSinksMutex.Lock()
+	defer channelLogSinksMutex.Unlock()
+
+	channelLogSinks = nil
+}
+
+func notifyChannelLogSinks(l *ChannelLog) {
+	channelLogSinksMutex.RLock()
+	defer channelLogSinksMutex.RUnlock()
+
+	for _, s := range channelLogSinks {
+		if err := s.Write(context.Background(), l); err != nil {
+			fmt.Fprintf(os.Stderr, "error writing channel log %s to sink: %s\n", l.UUID(), err)
+		}
+	}
+}
+
+// InitChannelLogSinks parses specs (config values like "stdout" and
+// "file:/var/log/courier/channels.jsonl") and registers the resulting sinks so that every
+// completed channel log is written to them. It's intended to be called once at server startup.
+func InitChannelLogSinks(specs []string) error {
+	sinks, err := ParseChannelLogSinks(specs)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sinks {
+		RegisterChannelLogSink(s)
+	}
+	return nil
+}
+
+// ParseChannelLogSinks builds sinks from config values like "stdout" and
+// "file:/var/log/courier/channels.jsonl". Any spec may carry an ":errors_only" suffix (e.g.
+// "file:/var/log/courier/channels.jsonl:errors_only") to wrap the resulting sink in ErrorsOnly,
+// for noisy production channels where only failures are worth keeping.
+func ParseChannelLogSinks(specs []string) ([]ChannelLogSink, error) {
+	sinks := make([]ChannelLogSink, 0, len(specs))
+
+	for _, spec := range specs {
+		errorsOnly := false
+		if strings.HasSuffix(spec, ":errors_only") {
+			errorsOnly = true
+			spec = strings.TrimSuffix(spec, ":errors_only")
+		}
+
+		var sink ChannelLogSink
+
+		switch {
+		case spec == "stdout":
+			sink = NewStdoutSink()
+
+		case strings.HasPrefix(spec, "file:"):
+			fileSink, err := NewFileSink(strings.TrimPrefix(spec, "file:"), defaultFileSinkMaxBytes, defaultFileSinkMaxAge)
+			if err != nil {
+				return nil, fmt.Errorf("error creating file log sink: %w", err)
+			}
+			sink = fileSink
+
+		default:
+			return nil, fmt.Errorf("unknown channel log sink: %s", spec)
+		}
+
+		if errorsOnly {
+			sink = ErrorsOnly(sink)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	return sinks, nil
+}
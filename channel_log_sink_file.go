@@ -0,0 +1,107 @@
+package courier
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileSinkMaxBytes int64         = 100 * 1024 * 1024 // 100MB
+	defaultFileSinkMaxAge   time.Duration = 24 * time.Hour
+)
+
+// FileSink is a ChannelLogSink that appends each log as a JSON line to path, rotating it once it
+// exceeds maxBytes (if > 0) or has been open longer than maxAge (if > 0). The rotated file is
+// renamed with a timestamp suffix and a new file opened in its place.
+type FileSink struct {
+	path     string
+	maxBytes int64
+	maxAge   time.Duration
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	openedOn time.Time
+}
+
+// NewFileSink creates a new file sink, opening (or creating) path
+func NewFileSink(path string, maxBytes int64, maxAge time.Duration) (*FileSink, error) {
+	s := &FileSink{path: path, maxBytes: maxBytes, maxAge: maxAge}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", s.path, err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", s.path, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+	s.openedOn = time.Now()
+	return nil
+}
+
+func (s *FileSink) rotate() error {
+	s.file.Close()
+
+	rotated := fmt.Sprintf("%s.%s", s.path, time.Now().Format("20060102150405"))
+	if err := os.Rename(s.path, rotated); err != nil {
+		return fmt.Errorf("error rotating %s: %w", s.path, err)
+	}
+
+	return s.open()
+}
+
+func (s *FileSink) Write(ctx context.Context, l *ChannelLog) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	needsRotation := (s.maxBytes > 0 && s.size >= s.maxBytes) || (s.maxAge > 0 && time.Since(s.openedOn) >= s.maxAge)
+	if needsRotation {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	n, err := s.file.Write(data)
+	if err != nil {
+		return fmt.Errorf("error writing to %s: %w", s.path, err)
+	}
+
+	s.size += int64(n)
+	return nil
+}
+
+// Close closes the underlying file, it should be called on server shutdown
+func (s *FileSink) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.file.Close()
+}
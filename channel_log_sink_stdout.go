@@ -0,0 +1,29 @@
+package courier
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// StdoutSink is a ChannelLogSink that pretty-prints the HTTP traces and errors of a channel log
+// to stdout, for use when developing a new channel handler locally.
+type StdoutSink struct{}
+
+func NewStdoutSink() *StdoutSink {
+	return &StdoutSink{}
+}
+
+func (s *StdoutSink) Write(ctx context.Context, l *ChannelLog) error {
+	fmt.Printf("\n=== %s %s %s (%dms)\n", l.Type(), l.UUID(), l.CreatedOn().Format(time.RFC3339), l.Elapsed().Milliseconds())
+
+	for _, h := range l.HTTPLogs() {
+		fmt.Printf("--- %d ---\n%+v\n\n", h.StatusCode, h)
+	}
+
+	for _, e := range l.Errors() {
+		fmt.Printf("!!! [%s] %s\n", e.Code(), e.Message())
+	}
+
+	return nil
+}
@@ -0,0 +1,102 @@
+package courier_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/courier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSinkRotation(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "channels.jsonl")
+
+	sink, err := courier.NewFileSink(path, 10, 0) // rotate after 10 bytes
+	require.NoError(t, err)
+	defer sink.Close()
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	clog.End()
+
+	require.NoError(t, sink.Write(context.Background(), clog))
+	require.NoError(t, sink.Write(context.Background(), clog))
+
+	entries, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, len(entries), 2, "expected original file plus at least one rotated file")
+}
+
+func TestErrorsOnlySink(t *testing.T) {
+	var written []*courier.ChannelLog
+	sink := courier.ErrorsOnly(recordingSink(func(l *courier.ChannelLog) { written = append(written, l) }))
+
+	ok := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	ok.End()
+	require.NoError(t, sink.Write(context.Background(), ok))
+	assert.Empty(t, written)
+
+	failed := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	failed.Error(courier.NewChannelError("test_error", "", "boom"))
+	failed.End()
+	require.NoError(t, sink.Write(context.Background(), failed))
+	assert.Len(t, written, 1)
+}
+
+type recordingSink func(*courier.ChannelLog)
+
+func (f recordingSink) Write(ctx context.Context, l *courier.ChannelLog) error {
+	f(l)
+	return nil
+}
+
+func TestInitChannelLogSinks(t *testing.T) {
+	defer courier.ClearChannelLogSinks()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "channels.jsonl")
+
+	err := courier.InitChannelLogSinks([]string{"stdout", "file:" + path})
+	require.NoError(t, err)
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	clog.End()
+
+	entries, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "expected the registered file sink to have created its log file")
+
+	courier.ClearChannelLogSinks()
+	err = courier.InitChannelLogSinks([]string{"unknown-sink"})
+	assert.EqualError(t, err, "unknown channel log sink: unknown-sink")
+}
+
+func TestParseChannelLogSinksErrorsOnlySuffix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "channels.jsonl")
+
+	sinks, err := courier.ParseChannelLogSinks([]string{"file:" + path + ":errors_only"})
+	require.NoError(t, err)
+	require.Len(t, sinks, 1)
+
+	ok := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	ok.End()
+	require.NoError(t, sinks[0].Write(context.Background(), ok))
+
+	failed := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	failed.Error(courier.NewChannelError("test_error", "", "boom"))
+	failed.End()
+	require.NoError(t, sinks[0].Write(context.Background(), failed))
+
+	entries, err := filepath.Glob(path + "*")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	data, err := os.ReadFile(entries[0])
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"), "expected only the errored log to have been written")
+}
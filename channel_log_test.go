@@ -0,0 +1,39 @@
+package courier_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/nyaruka/courier"
+	"github.com/nyaruka/courier/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelLogMarshalJSON(t *testing.T) {
+	ch := test.NewMockChannel("8eb23e93-5ecb-45ba-b726-3b064e0c56ab", "XX", "1234", "US", nil)
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, ch, nil)
+	clog.End()
+
+	data, err := json.Marshal(clog)
+	require.NoError(t, err)
+
+	var decoded struct {
+		UUID        string `json:"uuid"`
+		ChannelUUID string `json:"channel_uuid"`
+		Type        string `json:"type"`
+	}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, string(clog.UUID()), decoded.UUID)
+	assert.Equal(t, string(ch.UUID()), decoded.ChannelUUID)
+	assert.Equal(t, "msg_send", decoded.Type)
+
+	// a log with no channel (e.g. one created before its channel could be resolved) omits it
+	noChannelLog := courier.NewChannelLog(courier.ChannelLogTypeMsgSend, nil, nil)
+	noChannelLog.End()
+
+	data, err = json.Marshal(noChannelLog)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "channel_uuid")
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nyaruka/courier"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signRequest signs r with priv and sets the resulting Signature header, letting the caller
+// control exactly which headers are covered so tests can probe what happens when a sender
+// signs over less than the required set.
+func signRequest(t *testing.T, priv *rsa.PrivateKey, r *http.Request, keyID string, headers []string) {
+	signingString, err := buildSigningString(r, &signatureParams{headers: headers})
+	require.NoError(t, err)
+
+	hashed := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed[:])
+	require.NoError(t, err)
+
+	r.Header.Set("Signature", fmt.Sprintf(
+		`keyId="%s",algorithm="rsa-sha256",headers="%s",signature="%s"`,
+		keyID, strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig),
+	))
+}
+
+func TestWithHTTPSignature(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	resolve := KeyResolver(func(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+		return &priv.PublicKey, nil
+	})
+
+	clog := courier.NewChannelLog(courier.ChannelLogTypeEventReceive, nil, nil)
+
+	handler := WithHTTPSignature(resolve, func(r *http.Request) *courier.ChannelLog { return clog })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+	req.Header.Set("Date", "Tue, 07 Jun 2014 20:51:35 GMT")
+	signRequest(t, priv, req, "https://example.com/actor#main-key", []string{"(request-target)", "date"})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// changing the method after signing invalidates the "(request-target)" line and should fail
+	badReq := httptest.NewRequest("GET", "https://example.com/c/xx/receive", nil)
+	badReq.Header.Set("Date", req.Header.Get("Date"))
+	badReq.Header.Set("Signature", req.Header.Get("Signature"))
+
+	rec2 := httptest.NewRecorder()
+	handler.ServeHTTP(rec2, badReq)
+	assert.Equal(t, http.StatusUnauthorized, rec2.Code)
+	require.Len(t, clog.Errors(), 1)
+	assert.Equal(t, "signature_invalid", clog.Errors()[0].Code())
+}
+
+func TestWithHTTPSignatureRequiresBoundHeaders(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	resolve := KeyResolver(func(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+		return &priv.PublicKey, nil
+	})
+
+	newHandler := func() (http.Handler, *courier.ChannelLog) {
+		clog := courier.NewChannelLog(courier.ChannelLogTypeEventReceive, nil, nil)
+		handler := WithHTTPSignature(resolve, func(r *http.Request) *courier.ChannelLog { return clog })(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+		)
+		return handler, clog
+	}
+
+	cases := []struct {
+		label   string
+		headers []string
+	}{
+		{"date only, no request-target", []string{"date"}},
+		{"request-target only, no timestamp", []string{"(request-target)"}},
+		{"unrelated header only", []string{"content-type"}},
+	}
+
+	for _, tc := range cases {
+		handler, clog := newHandler()
+
+		req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+		req.Header.Set("Date", "Tue, 07 Jun 2014 20:51:35 GMT")
+		req.Header.Set("Content-Type", "application/json")
+		signRequest(t, priv, req, "https://example.com/actor#main-key", tc.headers)
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.Equalf(t, http.StatusUnauthorized, rec.Code, "case %q: a signature not bound to (request-target) and a timestamp must be rejected", tc.label)
+		require.Lenf(t, clog.Errors(), 1, "case %q", tc.label)
+		assert.Equal(t, "signature_invalid", clog.Errors()[0].Code())
+	}
+
+	// a signature that omits the headers param entirely is rejected too, as there's no default
+	handler, clog := newHandler()
+	req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+	req.Header.Set("Signature", `keyId="https://example.com/actor#main-key",algorithm="rsa-sha256",signature="AA=="`)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Len(t, clog.Errors(), 1)
+}
+
+func TestWithHTTPSignatureCreatedParam(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	resolve := KeyResolver(func(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+		return &priv.PublicKey, nil
+	})
+	clog := courier.NewChannelLog(courier.ChannelLogTypeEventReceive, nil, nil)
+	handler := WithHTTPSignature(resolve, func(r *http.Request) *courier.ChannelLog { return clog })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+	)
+
+	req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+
+	headers := []string{"(request-target)", "(created)"}
+	created := int64(1717000000)
+	signingString, err := buildSigningString(req, &signatureParams{headers: headers, created: created})
+	require.NoError(t, err)
+
+	hashed := sha256.Sum256([]byte(signingString))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, priv, crypto.SHA256, hashed[:])
+	require.NoError(t, err)
+
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="k1",algorithm="hs2019",created=%d,headers="(request-target) (created)",signature="%s"`,
+		created, base64.StdEncoding.EncodeToString(sig),
+	))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestWithHTTPSignatureEd25519(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	resolve := KeyResolver(func(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+		return pub, nil
+	})
+	clog := courier.NewChannelLog(courier.ChannelLogTypeEventReceive, nil, nil)
+	handler := WithHTTPSignature(resolve, func(r *http.Request) *courier.ChannelLog { return clog })(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }),
+	)
+
+	req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+	req.Header.Set("Date", "Tue, 07 Jun 2014 20:51:35 GMT")
+
+	headers := []string{"(request-target)", "date"}
+	signingString, err := buildSigningString(req, &signatureParams{headers: headers})
+	require.NoError(t, err)
+
+	sig := ed25519.Sign(priv, []byte(signingString))
+
+	req.Header.Set("Signature", fmt.Sprintf(
+		`keyId="https://example.com/actor#main-key",algorithm="hs2019",headers="%s",signature="%s"`,
+		strings.Join(headers, " "), base64.StdEncoding.EncodeToString(sig),
+	))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestMissingSignatureHeader(t *testing.T) {
+	clog := courier.NewChannelLog(courier.ChannelLogTypeEventReceive, nil, nil)
+
+	handler := WithHTTPSignature(
+		func(ctx context.Context, keyID string) (crypto.PublicKey, error) { return nil, fmt.Errorf("unused") },
+		func(r *http.Request) *courier.ChannelLog { return clog },
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler should not be reached")
+	}))
+
+	req := httptest.NewRequest("POST", "https://example.com/c/xx/receive", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	require.Len(t, clog.Errors(), 1)
+}
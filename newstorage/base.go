@@ -1,6 +1,9 @@
 package newstorage
 
-import "context"
+import (
+	"context"
+	"io"
+)
 
 // Storage is an interface that provides storing and retrieval of file like things
 type Storage interface {
@@ -16,6 +19,11 @@ type Storage interface {
 	// Put stores the given file at the given path
 	Put(ctx context.Context, path string, contentType string, contents []byte) (string, error)
 
+	// PutStream stores the contents read from body at the given path without requiring the
+	// caller to buffer the whole thing in memory first. size is the number of bytes that will
+	// be read from body, or -1 if unknown.
+	PutStream(ctx context.Context, path string, contentType string, body io.Reader, size int64) (string, error)
+
 	// BatchPut stores the given uploads, returning the URLs of the files after upload
 	BatchPut(ctx context.Context, uploads []*Upload) error
 }
@@ -0,0 +1,221 @@
+package newstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	gcstorage "cloud.google.com/go/storage"
+	"google.golang.org/api/option"
+)
+
+// maxGCSBatchConcurrency caps how many uploads BatchPut will have in flight at once
+const maxGCSBatchConcurrency = 8
+
+// GCS is a Storage implementation backed by Google Cloud Storage
+type GCS struct {
+	client   *gcstorage.Client
+	bucket   string
+	endpoint string
+
+	resumableMutex sync.Mutex
+	resumable      map[string]*gcstorage.Writer
+}
+
+// NewGCS creates a new GCS storage instance for the given bucket. If credentialsFile is empty,
+// application default credentials are used. endpoint overrides the default API host, and is
+// intended for pointing at a fake-gcs-server instance in tests.
+func NewGCS(ctx context.Context, bucket, credentialsFile, endpoint string) (*GCS, error) {
+	opts := make([]option.ClientOption, 0, 2)
+	if credentialsFile != "" {
+		opts = append(opts, option.WithCredentialsFile(credentialsFile))
+	}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint), option.WithoutAuthentication())
+	}
+
+	client, err := gcstorage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating GCS client: %w", err)
+	}
+
+	return &GCS{client: client, bucket: bucket, endpoint: endpoint, resumable: make(map[string]*gcstorage.Writer)}, nil
+}
+
+// GCSConfig holds the subset of the courier server config needed to construct a GCS backend.
+// CredentialsFile may be empty, in which case application default credentials are used - this is
+// the normal case when running on GCP. Endpoint is only set to point at a test double.
+type GCSConfig struct {
+	Bucket          string `mapstructure:"gcs_bucket"`
+	CredentialsFile string `mapstructure:"gcs_credentials_file"`
+	Endpoint        string `mapstructure:"gcs_endpoint"`
+}
+
+// NewGCSFromConfig creates a new GCS storage backend from cfg, as loaded from the courier
+// server's config file/environment
+func NewGCSFromConfig(ctx context.Context, cfg *GCSConfig) (*GCS, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("gcs_bucket is required to use the GCS storage backend")
+	}
+	return NewGCS(ctx, cfg.Bucket, cfg.CredentialsFile, cfg.Endpoint)
+}
+
+func (s *GCS) Name() string { return "GCS" }
+
+func (s *GCS) Test(ctx context.Context) error {
+	_, err := s.client.Bucket(s.bucket).Attrs(ctx)
+	return err
+}
+
+func (s *GCS) Get(ctx context.Context, path string) (string, []byte, error) {
+	r, err := s.client.Bucket(s.bucket).Object(trimLeadingSlash(path)).NewReader(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("error reading %s from GCS: %w", path, err)
+	}
+	defer r.Close()
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return r.Attrs.ContentType, body, nil
+}
+
+func (s *GCS) Put(ctx context.Context, path string, contentType string, contents []byte) (string, error) {
+	return s.PutStream(ctx, path, contentType, bytes.NewReader(contents), int64(len(contents)))
+}
+
+func (s *GCS) PutStream(ctx context.Context, path string, contentType string, body io.Reader, size int64) (string, error) {
+	obj := s.client.Bucket(s.bucket).Object(trimLeadingSlash(path))
+	w := obj.NewWriter(ctx)
+	w.ContentType = contentType
+	w.ChunkSize = 0 // send as a single request rather than negotiating a resumable session
+
+	if _, err := io.Copy(w, body); err != nil {
+		w.Close()
+		return "", fmt.Errorf("error writing %s to GCS: %w", path, err)
+	}
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("error closing GCS writer for %s: %w", path, err)
+	}
+
+	return s.url(path), nil
+}
+
+// BatchPut uploads the given files to GCS with bounded concurrency, setting URL on each upload
+// that succeeds and Error on each that fails - it never returns an error itself so that one bad
+// upload doesn't stop the others from being reported.
+func (s *GCS) BatchPut(ctx context.Context, uploads []*Upload) error {
+	sem := make(chan struct{}, maxGCSBatchConcurrency)
+	wg := sync.WaitGroup{}
+
+	for _, u := range uploads {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(u *Upload) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			url, err := s.Put(ctx, u.Path, u.ContentType, u.Body)
+			if err != nil {
+				u.Error = err
+				return
+			}
+			u.URL = url
+		}(u)
+	}
+
+	wg.Wait()
+	return nil
+}
+
+// resumableSessionCounter generates the location keys handed out by Start - the GCS client only
+// opens the real resumable session lazily, on the first Append, so these don't need to come from
+// GCS itself
+var resumableSessionCounter atomic.Int64
+
+// Start begins a new resumable upload at path, returning a handle that Append and Finish use to
+// identify it. The underlying GCS resumable session isn't opened until the first Append.
+func (s *GCS) Start(ctx context.Context, path string, contentType string) (*ResumableUpload, error) {
+	path = trimLeadingSlash(path)
+
+	w := s.client.Bucket(s.bucket).Object(path).NewWriter(ctx)
+	w.ContentType = contentType
+
+	location := fmt.Sprintf("%s#%d", path, resumableSessionCounter.Add(1))
+
+	s.resumableMutex.Lock()
+	s.resumable[location] = w
+	s.resumableMutex.Unlock()
+
+	return &ResumableUpload{Path: path, Location: location}, nil
+}
+
+// Append writes the next chunk of a resumable upload started by Start, returning the new total
+// offset into the upload. The offset is counted here from the bytes the GCS client's writer
+// accepted, which may still be buffered locally; no PATCH is sent per chunk and the offset is
+// not read from a Range response header.
+func (s *GCS) Append(ctx context.Context, upload *ResumableUpload, chunk []byte) (int64, error) {
+	s.resumableMutex.Lock()
+	w, found := s.resumable[upload.Location]
+	s.resumableMutex.Unlock()
+
+	if !found {
+		return 0, fmt.Errorf("unknown or already finished resumable upload %s", upload.Location)
+	}
+
+	n, err := w.Write(chunk)
+	if err != nil {
+		return 0, fmt.Errorf("error appending to resumable upload %s: %w", upload.Location, err)
+	}
+
+	upload.Offset += int64(n)
+	return upload.Offset, nil
+}
+
+// Finish commits a resumable upload started by Start, optionally verifying it against digest (a
+// hex-encoded MD5 of the full contents), and returns the final URL of the stored file.
+func (s *GCS) Finish(ctx context.Context, upload *ResumableUpload, digest string) (string, error) {
+	s.resumableMutex.Lock()
+	w, found := s.resumable[upload.Location]
+	delete(s.resumable, upload.Location)
+	s.resumableMutex.Unlock()
+
+	if !found {
+		return "", fmt.Errorf("unknown or already finished resumable upload %s", upload.Location)
+	}
+
+	if err := w.Close(); err != nil {
+		return "", fmt.Errorf("error finishing resumable upload %s: %w", upload.Location, err)
+	}
+
+	if digest != "" {
+		if got := hex.EncodeToString(w.Attrs().MD5); !strings.EqualFold(got, digest) {
+			return "", fmt.Errorf("digest mismatch for resumable upload %s: expected %s, got %s", upload.Location, digest, got)
+		}
+	}
+
+	return s.url(upload.Path), nil
+}
+
+func (s *GCS) url(path string) string {
+	path = trimLeadingSlash(path)
+	if s.endpoint != "" {
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(s.endpoint, "/"), s.bucket, path)
+	}
+	return fmt.Sprintf("https://storage.googleapis.com/%s/%s", s.bucket, path)
+}
+
+func trimLeadingSlash(path string) string {
+	return strings.TrimPrefix(path, "/")
+}
+
+var _ Storage = (*GCS)(nil)
+var _ Resumable = (*GCS)(nil)
@@ -0,0 +1,215 @@
+package newstorage_test
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/nyaruka/courier/newstorage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGCS is a minimal in-process stand-in for the GCS JSON API, just enough of it to exercise
+// our client against (bucket metadata, simple media upload, resumable upload, media download).
+type fakeGCS struct {
+	url          string
+	mutex        sync.Mutex
+	objects      map[string][]byte
+	contentTypes map[string]string
+}
+
+var fakeGCSSessionCounter atomic.Int64
+
+func newFakeGCS() *httptest.Server {
+	f := &fakeGCS{objects: make(map[string][]byte), contentTypes: make(map[string]string)}
+	server := httptest.NewServer(http.HandlerFunc(f.serve))
+	f.url = server.URL
+	return server
+}
+
+func (f *fakeGCS) serve(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.Method == "GET" && r.URL.Path == "/b/test-bucket":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"name": "test-bucket"})
+
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/test-bucket/o" && r.URL.Query().Get("uploadType") == "resumable":
+		sessionID := fmt.Sprintf("sess-%d", fakeGCSSessionCounter.Add(1))
+		location := fmt.Sprintf("%s/upload/storage/v1/b/test-bucket/o?uploadType=resumable&name=%s&upload_id=%s",
+			f.url, url.QueryEscape(r.URL.Query().Get("name")), sessionID)
+		w.Header().Set("Location", location)
+		w.WriteHeader(http.StatusOK)
+
+	case r.Method == "PUT" && r.URL.Query().Get("upload_id") != "":
+		name := r.URL.Query().Get("name")
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		f.mutex.Lock()
+		f.objects[name] = body
+		f.mutex.Unlock()
+
+		sum := md5.Sum(body)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"name": name, "bucket": "test-bucket", "md5Hash": base64.StdEncoding.EncodeToString(sum[:])})
+
+	case r.Method == "POST" && r.URL.Path == "/upload/storage/v1/b/test-bucket/o":
+		name := r.URL.Query().Get("name")
+		body := make([]byte, r.ContentLength)
+		if _, err := io.ReadFull(r.Body, body); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		f.mutex.Lock()
+		f.objects[name] = body
+		f.contentTypes[name] = r.Header.Get("Content-Type")
+		f.mutex.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"name": name, "bucket": "test-bucket", "contentType": r.Header.Get("Content-Type")})
+
+	case r.Method == "GET" && strings.HasPrefix(r.URL.Path, "/b/test-bucket/o/") && r.URL.Query().Get("alt") == "media":
+		name, err := url.PathUnescape(strings.TrimPrefix(r.URL.Path, "/b/test-bucket/o/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.mutex.Lock()
+		body, found := f.objects[name]
+		contentType := f.contentTypes[name]
+		f.mutex.Unlock()
+
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Write(body)
+
+	default:
+		http.Error(w, fmt.Sprintf("unhandled request %s %s", r.Method, r.URL.String()), http.StatusNotImplemented)
+	}
+}
+
+func TestNewGCSFromConfig(t *testing.T) {
+	server := newFakeGCS()
+	defer server.Close()
+
+	ctx := context.Background()
+
+	_, err := newstorage.NewGCSFromConfig(ctx, &newstorage.GCSConfig{Endpoint: server.URL})
+	assert.EqualError(t, err, "gcs_bucket is required to use the GCS storage backend")
+
+	store, err := newstorage.NewGCSFromConfig(ctx, &newstorage.GCSConfig{Bucket: "test-bucket", Endpoint: server.URL})
+	require.NoError(t, err)
+	assert.Equal(t, "GCS", store.Name())
+}
+
+func TestGCS(t *testing.T) {
+	server := newFakeGCS()
+	defer server.Close()
+
+	ctx := context.Background()
+	store, err := newstorage.NewGCS(ctx, "test-bucket", "", server.URL)
+	require.NoError(t, err)
+
+	assert.Equal(t, "GCS", store.Name())
+
+	url, err := store.Put(ctx, "/foo/bar.txt", "text/plain", []byte("hello world"))
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/test-bucket/foo/bar.txt", url)
+
+	contentType, body, err := store.Get(ctx, "/foo/bar.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain", contentType)
+	assert.Equal(t, []byte("hello world"), body)
+
+	uploads := []*newstorage.Upload{
+		{Path: "a.txt", Body: []byte("aaa"), ContentType: "text/plain"},
+		{Path: "b.txt", Body: []byte("bbb"), ContentType: "text/plain"},
+	}
+	err = store.BatchPut(ctx, uploads)
+	require.NoError(t, err)
+
+	for _, u := range uploads {
+		assert.NoError(t, u.Error)
+		assert.NotEmpty(t, u.URL)
+	}
+}
+
+func TestGCSResumable(t *testing.T) {
+	server := newFakeGCS()
+	defer server.Close()
+
+	ctx := context.Background()
+	store, err := newstorage.NewGCS(ctx, "test-bucket", "", server.URL)
+	require.NoError(t, err)
+
+	upload, err := store.Start(ctx, "/attachments/voice.ogg", "audio/ogg")
+	require.NoError(t, err)
+	assert.Equal(t, "attachments/voice.ogg", upload.Path)
+
+	offset, err := store.Append(ctx, upload, []byte("hello "))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), offset)
+
+	offset, err = store.Append(ctx, upload, []byte("world"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(11), offset)
+
+	finalURL, err := store.Finish(ctx, upload, "")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/test-bucket/attachments/voice.ogg", finalURL)
+
+	// the upload should no longer be usable once finished
+	_, err = store.Append(ctx, upload, []byte("more"))
+	assert.Error(t, err)
+}
+
+func TestGCSResumableDigest(t *testing.T) {
+	server := newFakeGCS()
+	defer server.Close()
+
+	ctx := context.Background()
+	store, err := newstorage.NewGCS(ctx, "test-bucket", "", server.URL)
+	require.NoError(t, err)
+
+	content := []byte("hello world")
+	sum := md5.Sum(content)
+	digest := hex.EncodeToString(sum[:])
+
+	// a digest matching the uploaded content is accepted
+	upload, err := store.Start(ctx, "/attachments/match.ogg", "audio/ogg")
+	require.NoError(t, err)
+	_, err = store.Append(ctx, upload, content)
+	require.NoError(t, err)
+	finalURL, err := store.Finish(ctx, upload, digest)
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/test-bucket/attachments/match.ogg", finalURL)
+
+	// a digest not matching the uploaded content is rejected
+	upload, err = store.Start(ctx, "/attachments/mismatch.ogg", "audio/ogg")
+	require.NoError(t, err)
+	_, err = store.Append(ctx, upload, content)
+	require.NoError(t, err)
+	_, err = store.Finish(ctx, upload, "0000000000000000000000000000000")
+	assert.ErrorContains(t, err, "digest mismatch")
+}
@@ -0,0 +1,30 @@
+package newstorage
+
+import "context"
+
+// ResumableUpload is an opaque handle to an in-progress resumable upload, returned by Start and
+// threaded through the subsequent Append / Finish calls. Implementations stash whatever they
+// need to identify the upload in Location (for GCS, a key to the client-side writer it holds
+// open for the upload, not a GCS session URI).
+type ResumableUpload struct {
+	Path     string
+	Location string
+	Offset   int64
+}
+
+// Resumable is implemented by storage backends that can accept an upload in chunks, e.g. so a
+// large MMS attachment or voice recording can be streamed in from its origin without buffering
+// the whole thing in memory. With GCS, an upload whose Append has failed can't be continued and
+// has to be started again.
+type Resumable interface {
+	// Start begins a new resumable upload at path, returning a handle identifying it
+	Start(ctx context.Context, path string, contentType string) (*ResumableUpload, error)
+
+	// Append adds the next chunk onto the upload and returns the new offset, i.e. the total
+	// number of bytes appended so far
+	Append(ctx context.Context, upload *ResumableUpload, chunk []byte) (int64, error)
+
+	// Finish commits the upload, optionally verifying it against digest (e.g. an MD5 or SHA256
+	// hex digest of the full contents), and returns the final URL of the stored file
+	Finish(ctx context.Context, upload *ResumableUpload, digest string) (string, error)
+}